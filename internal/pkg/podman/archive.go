@@ -0,0 +1,57 @@
+package podman
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"path"
+)
+
+// CopyToContainer streams r, which must already be a tar archive, into
+// container at destPath, exactly like `podman cp`. This is how pack8s
+// injects kubeconfigs, manifests, and binaries into the dnsmasq,
+// registry, and node containers it manages.
+func (hnd Handle) CopyToContainer(container, destPath string, r io.Reader) error {
+	return hnd.backend.CopyToContainer(container, destPath, r)
+}
+
+// CopyFromContainer streams a tar archive of srcPath out of container
+// into w.
+func (hnd Handle) CopyFromContainer(container, srcPath string, w io.Writer) error {
+	return hnd.backend.CopyFromContainer(container, srcPath, w)
+}
+
+// CopyFile wraps contents into a single-entry tar archive named after
+// destPath's base name and streams it into container at destPath's
+// directory, as a convenience over CopyToContainer for the common case
+// of injecting a single file.
+func (hnd Handle) CopyFile(container, destPath string, contents []byte, mode int64) error {
+	archive, err := singleFileTar(destPath, contents, mode)
+	if err != nil {
+		return err
+	}
+	return hnd.CopyToContainer(container, path.Dir(destPath), archive)
+}
+
+// singleFileTar builds a single-entry tar archive named after
+// destPath's base name, containing contents with the given mode.
+func singleFileTar(destPath string, contents []byte, mode int64) (io.Reader, error) {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name: path.Base(destPath),
+		Mode: mode,
+		Size: int64(len(contents)),
+	}); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(contents); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
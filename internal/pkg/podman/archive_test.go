@@ -0,0 +1,43 @@
+package podman
+
+import (
+	"archive/tar"
+	"io"
+	"testing"
+)
+
+func TestSingleFileTar(t *testing.T) {
+	contents := []byte("hello world")
+
+	r, err := singleFileTar("/etc/pack8s/config.yaml", contents, 0644)
+	if err != nil {
+		t.Fatalf("singleFileTar: %v", err)
+	}
+
+	tr := tar.NewReader(r)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatalf("reading tar header: %v", err)
+	}
+	if hdr.Name != "config.yaml" {
+		t.Fatalf("entry name = %q, want %q", hdr.Name, "config.yaml")
+	}
+	if hdr.Mode != 0644 {
+		t.Fatalf("entry mode = %o, want %o", hdr.Mode, 0644)
+	}
+	if hdr.Size != int64(len(contents)) {
+		t.Fatalf("entry size = %d, want %d", hdr.Size, len(contents))
+	}
+
+	got, err := io.ReadAll(tr)
+	if err != nil {
+		t.Fatalf("reading tar entry body: %v", err)
+	}
+	if string(got) != string(contents) {
+		t.Fatalf("entry body = %q, want %q", got, contents)
+	}
+
+	if _, err := tr.Next(); err != io.EOF {
+		t.Fatalf("expected a single-entry archive, got extra entry (err=%v)", err)
+	}
+}
@@ -0,0 +1,33 @@
+package podman
+
+import (
+	"context"
+	"io"
+
+	"github.com/fromanirh/pack8s/iopodman"
+)
+
+// Backend abstracts the wire protocol used to talk to the Podman service.
+// Upstream Podman dropped varlink in favour of a REST-style HTTP API
+// starting with v2, so Handle is built on top of this interface rather
+// than calling into iopodman directly: it lets pack8s keep talking to
+// older varlink-only Podman installs while also supporting the REST API
+// that every recent release exposes over a unix socket or TCP.
+type Backend interface {
+	ListContainers() ([]iopodman.Container, error)
+	ListImages() ([]iopodman.Image, error)
+	CreateContainer(conf iopodman.Create) (string, error)
+	RemoveContainer(id string, force, removeVolumes bool) (string, error)
+	StartContainer(id string) (string, error)
+	StopContainer(id string, timeout int64) (string, error)
+	WaitContainer(id string, interval int64) (int64, error)
+	PullImage(ref string, opts PullImageOpts, progress func(PullEvent)) error
+	GetVolumes(namesOrIDs []string, all bool) ([]iopodman.Volume, error)
+	VolumeCreate(opts iopodman.VolumeCreateOpts) (string, error)
+	VolumeRemove(opts iopodman.VolumeRemoveOpts) error
+	Exec(container string, args []string, opts ExecOpts) (int, error)
+	CopyToContainer(container, destPath string, r io.Reader) error
+	CopyFromContainer(container, srcPath string, w io.Writer) error
+	Events(ctx context.Context, filter EventFilter) (<-chan Event, error)
+	Close() error
+}
@@ -0,0 +1,548 @@
+package podman
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fromanirh/pack8s/iopodman"
+)
+
+// restAPIVersion is the libpod REST API version pack8s speaks. Podman
+// keeps the libpod API backward compatible across point releases, so a
+// single pinned version is enough for our purposes.
+const restAPIVersion = "v3.0.0"
+
+// restBackend talks to the Podman v2+ REST API over a unix socket or a
+// TCP connection. It is the Backend implementation new Podman releases
+// should use once varlink support goes away upstream.
+type restBackend struct {
+	ctx     context.Context
+	client  *http.Client
+	baseURL string
+}
+
+func newRESTBackend(ctx context.Context, connection string) (Backend, error) {
+	switch {
+	case strings.HasPrefix(connection, "http+unix://"):
+		socketPath := strings.TrimPrefix(connection, "http+unix://")
+		return &restBackend{
+			ctx: ctx,
+			client: &http.Client{
+				Transport: &http.Transport{
+					DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+						var d net.Dialer
+						return d.DialContext(ctx, "unix", socketPath)
+					},
+				},
+			},
+			baseURL: "http://d",
+		}, nil
+
+	case strings.HasPrefix(connection, "tcp://"):
+		return &restBackend{
+			ctx:     ctx,
+			client:  &http.Client{},
+			baseURL: "http://" + strings.TrimPrefix(connection, "tcp://"),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported REST connection string: %q", connection)
+	}
+}
+
+func (be *restBackend) url(path string) string {
+	return fmt.Sprintf("%s/%s/libpod%s", be.baseURL, restAPIVersion, path)
+}
+
+// do issues an HTTP request against the libpod API, setting contentType
+// on the request only when body is non-nil. Pass "" when body carries no
+// particular content type (e.g. a GET with no body).
+func (be *restBackend) do(method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(be.ctx, method, be.url(path), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil && contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := be.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("podman REST API: %s %s: %s: %s", method, path, resp.Status, string(msg))
+	}
+	return resp, nil
+}
+
+// doJSON is do with the "application/json" content type every JSON
+// request body/response pair in this file uses.
+func (be *restBackend) doJSON(method, path string, body io.Reader) (*http.Response, error) {
+	return be.do(method, path, "application/json", body)
+}
+
+func (be *restBackend) decode(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// restContainer is the subset of libpod's /containers/json response
+// pack8s actually looks at.
+type restContainer struct {
+	Id    string   `json:"Id"`
+	Names []string `json:"Names"`
+}
+
+func (be *restBackend) ListContainers() ([]iopodman.Container, error) {
+	resp, err := be.doJSON(http.MethodGet, "/containers/json?all=true", nil)
+	if err != nil {
+		return nil, err
+	}
+	var restContainers []restContainer
+	if err := be.decode(resp, &restContainers); err != nil {
+		return nil, err
+	}
+
+	containers := make([]iopodman.Container, 0, len(restContainers))
+	for _, c := range restContainers {
+		name := ""
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		containers = append(containers, iopodman.Container{
+			Id:    c.Id,
+			Names: name,
+		})
+	}
+	return containers, nil
+}
+
+type restImage struct {
+	Id       string   `json:"Id"`
+	RepoTags []string `json:"RepoTags"`
+}
+
+func (be *restBackend) ListImages() ([]iopodman.Image, error) {
+	resp, err := be.doJSON(http.MethodGet, "/images/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	var restImages []restImage
+	if err := be.decode(resp, &restImages); err != nil {
+		return nil, err
+	}
+
+	images := make([]iopodman.Image, 0, len(restImages))
+	for _, i := range restImages {
+		images = append(images, iopodman.Image{
+			Id:       i.Id,
+			RepoTags: i.RepoTags,
+		})
+	}
+	return images, nil
+}
+
+// specGenFromCreate translates the subset of the old io.podman varlink
+// Create IDL struct that pack8s actually sets into a libpod
+// specgen.SpecGenerator payload, which is a different JSON shape
+// entirely (nesting, field names, namespace/mount/port config) and
+// cannot be produced by re-serializing Create verbatim. Args[0] is the
+// image to create the container from and the remaining elements, if
+// any, override its command, mirroring `podman create IMAGE [COMMAND
+// ...]` and the varlink Create call pack8s used to make.
+func specGenFromCreate(conf iopodman.Create) (map[string]interface{}, error) {
+	if len(conf.Args) == 0 {
+		return nil, fmt.Errorf("podman: CreateContainer: conf.Args must contain at least the image to create")
+	}
+
+	spec := map[string]interface{}{
+		"image": conf.Args[0],
+	}
+	if len(conf.Args) > 1 {
+		spec["command"] = conf.Args[1:]
+	}
+	if conf.Name != nil {
+		spec["name"] = *conf.Name
+	}
+	return spec, nil
+}
+
+func (be *restBackend) CreateContainer(conf iopodman.Create) (string, error) {
+	spec, err := specGenFromCreate(conf)
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	resp, err := be.doJSON(http.MethodPost, "/containers/create", strings.NewReader(string(body)))
+	if err != nil {
+		return "", err
+	}
+	var created struct {
+		Id string `json:"Id"`
+	}
+	if err := be.decode(resp, &created); err != nil {
+		return "", err
+	}
+	return created.Id, nil
+}
+
+func (be *restBackend) RemoveContainer(id string, force, removeVolumes bool) (string, error) {
+	path := fmt.Sprintf("/containers/%s?force=%v&v=%v", id, force, removeVolumes)
+	resp, err := be.doJSON(http.MethodDelete, path, nil)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return id, nil
+}
+
+func (be *restBackend) StartContainer(id string) (string, error) {
+	resp, err := be.doJSON(http.MethodPost, fmt.Sprintf("/containers/%s/start", id), nil)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return id, nil
+}
+
+func (be *restBackend) StopContainer(id string, timeout int64) (string, error) {
+	resp, err := be.doJSON(http.MethodPost, fmt.Sprintf("/containers/%s/stop?timeout=%d", id, timeout), nil)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return id, nil
+}
+
+func (be *restBackend) WaitContainer(id string, interval int64) (int64, error) {
+	resp, err := be.doJSON(http.MethodPost, fmt.Sprintf("/containers/%s/wait", id), nil)
+	if err != nil {
+		return 0, err
+	}
+	var result struct {
+		StatusCode int64 `json:"StatusCode"`
+	}
+	if err := be.decode(resp, &result); err != nil {
+		return 0, err
+	}
+	return result.StatusCode, nil
+}
+
+// restPullEvent mirrors the json-lines Podman streams from
+// /images/pull: one object per layer status update, finishing with an
+// object that carries the pulled image's digest.
+type restPullEvent struct {
+	Id             string `json:"id"`
+	Status         string `json:"status"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	Error string `json:"error"`
+}
+
+func (be *restBackend) PullImage(ref string, opts PullImageOpts, progress func(PullEvent)) error {
+	path := fmt.Sprintf("/images/pull?reference=%s", ref)
+	if opts.Registry != "" {
+		path += "&" + "Registry=" + opts.Registry
+	}
+	if opts.TLSVerify != nil {
+		path += fmt.Sprintf("&tlsVerify=%v", *opts.TLSVerify)
+	}
+
+	req, err := http.NewRequestWithContext(be.ctx, http.MethodPost, be.url(path), nil)
+	if err != nil {
+		return err
+	}
+	if header, ok := registryAuthHeader(opts); ok {
+		req.Header.Set("X-Registry-Auth", header)
+	}
+
+	resp, err := be.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("podman REST API: POST %s: %s: %s", path, resp.Status, string(msg))
+	}
+
+	digest := ""
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event restPullEvent
+		if err := decoder.Decode(&event); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if event.Error != "" {
+			return fmt.Errorf("pulling %s: %s", ref, event.Error)
+		}
+		layer := event.Id
+		if strings.HasPrefix(event.Id, "sha256:") {
+			digest = event.Id
+			layer = ""
+		}
+		if progress != nil {
+			progress(PullEvent{
+				Layer:   layer,
+				Status:  event.Status,
+				Current: event.ProgressDetail.Current,
+				Total:   event.ProgressDetail.Total,
+				Digest:  digest,
+			})
+		}
+	}
+	return nil
+}
+
+// registryAuthHeader base64-encodes opts into the X-Registry-Auth header
+// Podman's REST API expects, following the same format `docker login`
+// stores in its config.
+func registryAuthHeader(opts PullImageOpts) (string, bool) {
+	if opts.Username == "" && opts.Token == "" {
+		return "", false
+	}
+	auth := map[string]string{}
+	if opts.Token != "" {
+		auth["identitytoken"] = opts.Token
+	} else {
+		auth["username"] = opts.Username
+		auth["password"] = opts.Password
+	}
+	encoded, err := json.Marshal(auth)
+	if err != nil {
+		return "", false
+	}
+	return base64.URLEncoding.EncodeToString(encoded), true
+}
+
+func (be *restBackend) Exec(container string, args []string, opts ExecOpts) (int, error) {
+	if opts.Stdin != nil {
+		// do() is a plain request/response round trip with no connection
+		// hijack, so there is no channel to stream opts.Stdin through
+		// once the exec is started. Fail loudly instead of silently
+		// promising AttachStdin and leaving the caller hanging.
+		return -1, fmt.Errorf("podman: Exec with Stdin is not supported by the REST backend")
+	}
+
+	createBody, err := json.Marshal(map[string]interface{}{
+		"Cmd":          args,
+		"Env":          opts.Env,
+		"WorkingDir":   opts.WorkingDir,
+		"Tty":          opts.Tty,
+		"AttachStdin":  false,
+		"AttachStdout": true,
+		"AttachStderr": true,
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	resp, err := be.doJSON(http.MethodPost, fmt.Sprintf("/containers/%s/exec", container), strings.NewReader(string(createBody)))
+	if err != nil {
+		return -1, err
+	}
+	var created struct {
+		Id string `json:"Id"`
+	}
+	if err := be.decode(resp, &created); err != nil {
+		return -1, err
+	}
+
+	startBody, err := json.Marshal(map[string]interface{}{"Tty": opts.Tty})
+	if err != nil {
+		return -1, err
+	}
+	startResp, err := be.doJSON(http.MethodPost, fmt.Sprintf("/exec/%s/start", created.Id), strings.NewReader(string(startBody)))
+	if err != nil {
+		return -1, err
+	}
+	defer startResp.Body.Close()
+
+	if opts.Tty {
+		if _, err := io.Copy(stdoutOrDiscard(opts), startResp.Body); err != nil {
+			return -1, err
+		}
+	} else if err := demuxAttachStream(startResp.Body, stdoutOrDiscard(opts), stderrOrDiscard(opts)); err != nil {
+		return -1, err
+	}
+
+	inspectResp, err := be.doJSON(http.MethodGet, fmt.Sprintf("/exec/%s/json", created.Id), nil)
+	if err != nil {
+		return -1, err
+	}
+	var inspected struct {
+		ExitCode int `json:"ExitCode"`
+	}
+	if err := be.decode(inspectResp, &inspected); err != nil {
+		return -1, err
+	}
+	return inspected.ExitCode, nil
+}
+
+func (be *restBackend) GetVolumes(namesOrIDs []string, all bool) ([]iopodman.Volume, error) {
+	resp, err := be.doJSON(http.MethodGet, "/volumes/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	var restVolumes []struct {
+		Name       string `json:"Name"`
+		Mountpoint string `json:"Mountpoint"`
+	}
+	if err := be.decode(resp, &restVolumes); err != nil {
+		return nil, err
+	}
+
+	volumes := make([]iopodman.Volume, 0, len(restVolumes))
+	for _, v := range restVolumes {
+		if !all && !contains(namesOrIDs, v.Name) {
+			continue
+		}
+		volumes = append(volumes, iopodman.Volume{
+			Name:       v.Name,
+			MountPoint: v.Mountpoint,
+		})
+	}
+	return volumes, nil
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
+
+func (be *restBackend) VolumeCreate(opts iopodman.VolumeCreateOpts) (string, error) {
+	body, err := json.Marshal(map[string]string{"Name": opts.VolumeName})
+	if err != nil {
+		return "", err
+	}
+	resp, err := be.doJSON(http.MethodPost, "/volumes/create", strings.NewReader(string(body)))
+	if err != nil {
+		return "", err
+	}
+	var created struct {
+		Name string `json:"Name"`
+	}
+	if err := be.decode(resp, &created); err != nil {
+		return "", err
+	}
+	return created.Name, nil
+}
+
+func (be *restBackend) VolumeRemove(opts iopodman.VolumeRemoveOpts) error {
+	for _, name := range opts.Volumes {
+		path := fmt.Sprintf("/volumes/%s?force=%v", name, opts.Force)
+		resp, err := be.doJSON(http.MethodDelete, path, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+	return nil
+}
+
+func (be *restBackend) CopyToContainer(container, destPath string, r io.Reader) error {
+	path := fmt.Sprintf("/containers/%s/archive?path=%s", container, url.QueryEscape(destPath))
+	resp, err := be.do(http.MethodPut, path, "application/x-tar", r)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (be *restBackend) CopyFromContainer(container, srcPath string, w io.Writer) error {
+	path := fmt.Sprintf("/containers/%s/archive?path=%s", container, url.QueryEscape(srcPath))
+	resp, err := be.do(http.MethodGet, path, "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// restEvent is the subset of libpod's /events payload Events looks at.
+type restEvent struct {
+	Type   string `json:"Type"`
+	Status string `json:"Status"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+}
+
+func (be *restBackend) Events(ctx context.Context, filter EventFilter) (<-chan Event, error) {
+	path := "/events?stream=true"
+	if restFilters := filter.restFilters(); restFilters != "" {
+		path += "&filters=" + url.QueryEscape(restFilters)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, be.url(path), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := be.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("podman REST API: GET /events: %s: %s", resp.Status, string(msg))
+	}
+
+	out := make(chan Event)
+	go func() {
+		defer close(out)
+		defer resp.Body.Close()
+
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			var ev restEvent
+			if err := decoder.Decode(&ev); err != nil {
+				if err != io.EOF && ctx.Err() == nil {
+					log.Printf("podman: events stream ended unexpectedly: %v", err)
+				}
+				return
+			}
+			event := Event{
+				Type:   ev.Type,
+				Status: ev.Status,
+				Name:   ev.Actor.Attributes["name"],
+				Id:     ev.Actor.ID,
+				Labels: ev.Actor.Attributes,
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (be *restBackend) Close() error {
+	return nil
+}
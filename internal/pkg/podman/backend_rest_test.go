@@ -0,0 +1,49 @@
+package podman
+
+import (
+	"testing"
+
+	"github.com/fromanirh/pack8s/iopodman"
+)
+
+func TestSpecGenFromCreateImageOnly(t *testing.T) {
+	name := "pack8s-test"
+	spec, err := specGenFromCreate(iopodman.Create{
+		Args: []string{"quay.io/pack8s/registry:latest"},
+		Name: &name,
+	})
+	if err != nil {
+		t.Fatalf("specGenFromCreate: %v", err)
+	}
+	if spec["image"] != "quay.io/pack8s/registry:latest" {
+		t.Fatalf("image = %v, want the image reference", spec["image"])
+	}
+	if spec["name"] != name {
+		t.Fatalf("name = %v, want %q", spec["name"], name)
+	}
+	if _, ok := spec["command"]; ok {
+		t.Fatalf("command should be omitted when no args follow the image, got %v", spec["command"])
+	}
+}
+
+func TestSpecGenFromCreateWithCommand(t *testing.T) {
+	spec, err := specGenFromCreate(iopodman.Create{
+		Args: []string{"quay.io/pack8s/registry:latest", "serve", "--port=5000"},
+	})
+	if err != nil {
+		t.Fatalf("specGenFromCreate: %v", err)
+	}
+	command, ok := spec["command"].([]string)
+	if !ok || len(command) != 2 || command[0] != "serve" || command[1] != "--port=5000" {
+		t.Fatalf("command = %v, want [serve --port=5000]", spec["command"])
+	}
+	if _, ok := spec["name"]; ok {
+		t.Fatalf("name should be omitted when conf.Name is nil, got %v", spec["name"])
+	}
+}
+
+func TestSpecGenFromCreateRequiresImage(t *testing.T) {
+	if _, err := specGenFromCreate(iopodman.Create{}); err == nil {
+		t.Fatal("expected an error when conf.Args has no image")
+	}
+}
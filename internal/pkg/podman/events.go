@@ -0,0 +1,107 @@
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// EventFilter narrows down which lifecycle events Events delivers.
+// Zero-valued fields match anything.
+type EventFilter struct {
+	// Type restricts events to objects of a given kind, e.g.
+	// "container", "pod", "image", "volume".
+	Type string
+	// Label restricts events to objects carrying this label, e.g.
+	// LabelGeneration.
+	Label string
+	// NamePrefix restricts events to containers whose name starts with
+	// this prefix, mirroring GetPrefixedContainers.
+	NamePrefix string
+}
+
+// restFilters renders the Type and Label parts of f into the JSON
+// `filters` query parameter libpod's /events endpoint accepts, so the
+// REST backend can have Podman do that filtering server-side instead of
+// decoding every event on the host just to throw most of them away.
+// NamePrefix has no REST equivalent (Podman only filters containers by
+// exact name/id), so it's left for EventFilter.matches to apply.
+func (f EventFilter) restFilters() string {
+	filters := map[string][]string{}
+	if f.Type != "" {
+		filters["type"] = []string{f.Type}
+	}
+	if f.Label != "" {
+		filters["label"] = []string{f.Label}
+	}
+	if len(filters) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(filters)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+func (f EventFilter) matches(ev Event) bool {
+	if f.Type != "" && f.Type != ev.Type {
+		return false
+	}
+	if f.Label != "" {
+		if _, ok := ev.Labels[f.Label]; !ok {
+			return false
+		}
+	}
+	if f.NamePrefix != "" && !strings.HasPrefix(ev.Name, f.NamePrefix) {
+		return false
+	}
+	return true
+}
+
+// Event is a single container/pod/image/volume lifecycle event, as
+// reported by Podman's events stream (create, start, died, remove,
+// pull, ...).
+type Event struct {
+	Type   string
+	Status string
+	Name   string
+	Id     string
+	Labels map[string]string
+}
+
+// Events streams lifecycle events matching filter until ctx is
+// cancelled, closing the returned channel when it is. Waiting on an
+// event predicate here is what replaces polling WaitContainer/
+// GetPrefixedContainers in a loop, which is racy when several
+// containers are coming up in parallel.
+func (hnd Handle) Events(ctx context.Context, filter EventFilter) (<-chan Event, error) {
+	raw, err := hnd.backend.Events(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan Event)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-raw:
+				if !ok {
+					return
+				}
+				if !filter.matches(ev) {
+					continue
+				}
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,56 @@
+package podman
+
+import "testing"
+
+func TestEventFilterMatches(t *testing.T) {
+	ev := Event{
+		Type:   "container",
+		Status: "start",
+		Name:   "pack8s-node01",
+		Id:     "abc123",
+		Labels: map[string]string{LabelGeneration: "1"},
+	}
+
+	cases := []struct {
+		name   string
+		filter EventFilter
+		want   bool
+	}{
+		{"empty filter matches anything", EventFilter{}, true},
+		{"matching type", EventFilter{Type: "container"}, true},
+		{"mismatching type", EventFilter{Type: "volume"}, false},
+		{"matching label", EventFilter{Label: LabelGeneration}, true},
+		{"missing label", EventFilter{Label: "io.kubevirt/other"}, false},
+		{"matching name prefix", EventFilter{NamePrefix: "pack8s-"}, true},
+		{"mismatching name prefix", EventFilter{NamePrefix: "other-"}, false},
+		{"all fields match", EventFilter{Type: "container", Label: LabelGeneration, NamePrefix: "pack8s-"}, true},
+		{"one field mismatches", EventFilter{Type: "container", Label: "io.kubevirt/other"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.filter.matches(ev); got != c.want {
+				t.Fatalf("matches() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestEventFilterRESTFilters(t *testing.T) {
+	if got := (EventFilter{}).restFilters(); got != "" {
+		t.Fatalf("empty filter should render to no filters, got %q", got)
+	}
+
+	got := EventFilter{Type: "container", Label: LabelGeneration}.restFilters()
+	want := `{"label":["io.kubevirt/pack8s.generation"],"type":["container"]}`
+	if got != want {
+		t.Fatalf("restFilters() = %q, want %q", got, want)
+	}
+
+	// NamePrefix has no REST equivalent and must not leak into the
+	// server-side filter, it's applied by matches instead.
+	got = EventFilter{NamePrefix: "pack8s-"}.restFilters()
+	if got != "" {
+		t.Fatalf("NamePrefix should not produce a REST filter, got %q", got)
+	}
+}
@@ -0,0 +1,61 @@
+package podman
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// ExecOpts configures Handle.Exec. When Tty is false, Stdout and Stderr
+// receive the command's output demultiplexed from Podman's attach
+// stream; when Tty is true Podman multiplexes both onto a single
+// stream, which is written to Stdout, matching what a real terminal
+// would see.
+type ExecOpts struct {
+	Stdin      io.Reader
+	Stdout     io.Writer
+	Stderr     io.Writer
+	Tty        bool
+	Env        []string
+	WorkingDir string
+}
+
+func stdoutOrDiscard(opts ExecOpts) io.Writer {
+	if opts.Stdout != nil {
+		return opts.Stdout
+	}
+	return io.Discard
+}
+
+func stderrOrDiscard(opts ExecOpts) io.Writer {
+	if opts.Stderr != nil {
+		return opts.Stderr
+	}
+	return io.Discard
+}
+
+// demuxAttachStream splits a non-TTY Podman attach/exec stream into
+// stdout and stderr. Each frame starts with an 8 byte header: byte 0 is
+// the stream id (1 = stdout, 2 = stderr), bytes 4-7 are the frame length
+// as a big-endian uint32.
+func demuxAttachStream(r io.Reader, stdout, stderr io.Writer) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		size := binary.BigEndian.Uint32(header[4:8])
+		frame := io.LimitReader(r, int64(size))
+
+		out := stdout
+		if header[0] == 2 {
+			out = stderr
+		}
+		if _, err := io.Copy(out, frame); err != nil {
+			return err
+		}
+	}
+}
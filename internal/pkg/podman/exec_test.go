@@ -0,0 +1,43 @@
+package podman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func frame(streamID byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = streamID
+	binary.BigEndian.PutUint32(header[4:8], uint32(len(payload)))
+	return append(header, []byte(payload)...)
+}
+
+func TestDemuxAttachStreamSplitsStdoutAndStderr(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(frame(1, "hello "))
+	stream.Write(frame(2, "uh oh"))
+	stream.Write(frame(1, "world"))
+
+	var stdout, stderr bytes.Buffer
+	if err := demuxAttachStream(&stream, &stdout, &stderr); err != nil {
+		t.Fatalf("demuxAttachStream: %v", err)
+	}
+
+	if stdout.String() != "hello world" {
+		t.Fatalf("stdout = %q, want %q", stdout.String(), "hello world")
+	}
+	if stderr.String() != "uh oh" {
+		t.Fatalf("stderr = %q, want %q", stderr.String(), "uh oh")
+	}
+}
+
+func TestDemuxAttachStreamEmpty(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if err := demuxAttachStream(&bytes.Buffer{}, &stdout, &stderr); err != nil {
+		t.Fatalf("demuxAttachStream on empty stream: %v", err)
+	}
+	if stdout.Len() != 0 || stderr.Len() != 0 {
+		t.Fatalf("expected no output, got stdout=%q stderr=%q", stdout.String(), stderr.String())
+	}
+}
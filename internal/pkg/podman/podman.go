@@ -86,75 +86,108 @@ func SprintError(methodname string, err error) string {
 }
 
 type Handle struct {
-	ctx  context.Context
-	conn *varlink.Connection
+	ctx     context.Context
+	backend Backend
 }
 
 const (
-	DefaultSocket string = "unix:/run/podman/io.podman"
+	// DefaultVarlinkSocket is where older Podman releases expose the
+	// io.podman varlink interface.
+	DefaultVarlinkSocket string = "unix:/run/podman/io.podman"
+	// DefaultRESTSocket is where Podman v2+ exposes the libpod REST API.
+	DefaultRESTSocket string = "http+unix:///run/podman/podman.sock"
 )
 
-func NewHandle(ctx context.Context) (Handle, error) {
-	log.Printf("connecting to %s", DefaultSocket)
-	conn, err := varlink.NewConnection(ctx, DefaultSocket)
-	log.Printf("connected to %s", DefaultSocket)
+// NewHandle connects to a Podman service and returns a Handle to drive it.
+//
+// connection selects both the endpoint and the wire protocol to use:
+//   - "" auto-detects, preferring the REST API socket over varlink
+//   - "varlink+unix://" or "unix:" talks varlink to a local socket
+//   - "http+unix://" talks the REST API to a local socket
+//   - "tcp://" talks the REST API over TCP
+//   - "ssh://user@host[:port]/path/to/podman.sock" tunnels either
+//     protocol to a remote host over SSH
+func NewHandle(ctx context.Context, connection string) (Handle, error) {
+	if connection == "" {
+		connection = detectConnection()
+	}
+
+	backend, err := newBackend(ctx, connection)
+	if err != nil {
+		return Handle{}, err
+	}
 	return Handle{
-		ctx:  ctx,
-		conn: conn,
-	}, err
+		ctx:     ctx,
+		backend: backend,
+	}, nil
 }
 
-func (hnd Handle) Terminal(container string, args []string, file *os.File) error {
-	detachKeys := ""
-	start := false
+// Close releases any resources the Handle's backend holds open, such as
+// an SSH-tunnelled connection's listener and underlying ssh.Client.
+func (hnd Handle) Close() error {
+	return hnd.backend.Close()
+}
 
-	err := iopodman.Attach().Call(hnd.ctx, hnd.conn, container, detachKeys, start)
-	if err != nil {
-		return err
-	}
+func newBackend(ctx context.Context, connection string) (Backend, error) {
+	switch {
+	case strings.HasPrefix(connection, "varlink+unix://"):
+		log.Printf("connecting to %s (varlink)", connection)
+		return newVarlinkBackend(ctx, "unix:"+strings.TrimPrefix(connection, "varlink+unix://"))
 
-	socks, err := iopodman.GetAttachSockets().Call(hnd.ctx, hnd.conn, container)
-	if err != nil {
-		return err
+	case strings.HasPrefix(connection, "unix:"):
+		log.Printf("connecting to %s (varlink)", connection)
+		return newVarlinkBackend(ctx, connection)
+
+	case strings.HasPrefix(connection, "http+unix://"), strings.HasPrefix(connection, "tcp://"):
+		log.Printf("connecting to %s (REST)", connection)
+		return newRESTBackend(ctx, connection)
+
+	case strings.HasPrefix(connection, "ssh://"):
+		log.Printf("connecting to %s (SSH tunnel)", connection)
+		return newSSHBackend(ctx, connection)
+
+	default:
+		return nil, fmt.Errorf("unsupported podman connection string: %q", connection)
 	}
+}
 
-	attached, err := os.OpenFile(socks.Io_socket, os.O_RDWR, 0644)
-	if err != nil {
-		return err
+// detectConnection probes the well-known Podman socket locations and
+// picks the REST API when available, falling back to varlink for older
+// Podman installs that don't expose it.
+func detectConnection() string {
+	if _, err := os.Stat(strings.TrimPrefix(DefaultRESTSocket, "http+unix://")); err == nil {
+		return DefaultRESTSocket
 	}
-	defer attached.Close()
+	return DefaultVarlinkSocket
+}
 
+// Terminal runs args inside container as an interactive session attached
+// to file, exactly like `podman exec -it`. It's built on top of Exec
+// rather than a dedicated attach call. It always passes file as Stdin,
+// which the REST backend's Exec rejects, so on that backend Terminal
+// returns that error without running anything.
+func (hnd Handle) Terminal(container string, args []string, file *os.File) error {
 	state, err := terminal.MakeRaw(int(file.Fd()))
 	if err != nil {
 		return err
 	}
 	defer terminal.Restore(int(file.Fd()), state)
 
-	errChan := make(chan error)
+	errChan := make(chan error, 1)
 
 	go func() {
 		interrupt := make(chan os.Signal, 1)
 		signal.Notify(interrupt, os.Interrupt)
 		<-interrupt
-		close(errChan)
+		errChan <- nil
 	}()
 
 	go func() {
-		_, err := io.Copy(file, attached)
-		errChan <- err
-	}()
-
-	go func() {
-		_, err := io.Copy(attached, file)
-		errChan <- err
-	}()
-
-	go func() {
-		err := iopodman.ExecContainer().Call(hnd.ctx, hnd.conn, iopodman.ExecOpts{
-			Name:       container,
-			Tty:        terminal.IsTerminal(int(file.Fd())),
-			Privileged: true,
-			Cmd:        args,
+		_, err := hnd.Exec(container, args, ExecOpts{
+			Stdin:  file,
+			Stdout: file,
+			Stderr: file,
+			Tty:    terminal.IsTerminal(int(file.Fd())),
 		})
 		errChan <- err
 	}()
@@ -162,18 +195,18 @@ func (hnd Handle) Terminal(container string, args []string, file *os.File) error
 	return <-errChan
 }
 
-func (hnd Handle) Exec(container string, args []string, out io.Writer) error {
-	return iopodman.ExecContainer().Call(hnd.ctx, hnd.conn, iopodman.ExecOpts{
-		Name:       container,
-		Tty:        true,
-		Privileged: true,
-		Cmd:        args,
-	})
+// Exec runs args inside container and returns its exit code so callers
+// can propagate failures from kubectl-style commands correctly. The
+// varlink backend does not report the command's own exit code: it
+// returns 0 when the exec call succeeds and 1 with the error when it
+// fails.
+func (hnd Handle) Exec(container string, args []string, opts ExecOpts) (int, error) {
+	return hnd.backend.Exec(container, args, opts)
 }
 
 func (hnd Handle) GetPrefixedContainers(prefix string) ([]iopodman.Container, error) {
 	ret := []iopodman.Container{}
-	containers, err := iopodman.ListContainers().Call(hnd.ctx, hnd.conn)
+	containers, err := hnd.backend.ListContainers()
 	if err != nil {
 		return ret, err
 	}
@@ -190,11 +223,15 @@ func (hnd Handle) GetPrefixedContainers(prefix string) ([]iopodman.Container, er
 	return ret, nil
 }
 
+func (hnd Handle) GetAllVolumes() ([]iopodman.Volume, error) {
+	return hnd.backend.GetVolumes([]string{}, true)
+}
+
 func (hnd Handle) GetPrefixedVolumes(prefix string) ([]iopodman.Volume, error) {
 	ret := []iopodman.Volume{}
 	args := []string{}
 	all := true
-	volumes, err := iopodman.GetVolumes().Call(hnd.ctx, hnd.conn, args, all)
+	volumes, err := hnd.backend.GetVolumes(args, all)
 	if err != nil {
 		return ret, err
 	}
@@ -230,49 +267,54 @@ func (hnd Handle) RemoveVolumes(volumes []iopodman.Volume) error {
 		log.Printf("removing volume %s @%s", vol.Name, vol.MountPoint)
 		volumeNames = append(volumeNames, vol.Name)
 	}
-	_, _, err := iopodman.VolumeRemove().Call(hnd.ctx, hnd.conn, iopodman.VolumeRemoveOpts{
+	return hnd.backend.VolumeRemove(iopodman.VolumeRemoveOpts{
 		Volumes: volumeNames,
 		Force:   true,
 	})
-	return err
 }
 
 func (hnd Handle) RemoveContainer(cont iopodman.Container, force, removeVolumes bool) (string, error) {
 	log.Printf("trying to remove: %s (%s) force=%v removeVolumes=%v\n", cont.Names, cont.Id, force, removeVolumes)
-	return iopodman.RemoveContainer().Call(hnd.ctx, hnd.conn, cont.Id, force, removeVolumes)
+	return hnd.backend.RemoveContainer(cont.Id, force, removeVolumes)
 }
 
 func (hnd Handle) CreateNamedVolume(name string) (string, error) {
-	return iopodman.VolumeCreate().Call(hnd.ctx, hnd.conn, iopodman.VolumeCreateOpts{
+	return hnd.backend.VolumeCreate(iopodman.VolumeCreateOpts{
 		VolumeName: name,
 	})
 }
 
 func (hnd Handle) CreateContainer(conf iopodman.Create) (string, error) {
-	return iopodman.CreateContainer().Call(hnd.ctx, hnd.conn, conf)
+	return hnd.backend.CreateContainer(conf)
 }
 
 func (hnd Handle) StopContainer(name string, timeout int64) (string, error) {
-	return iopodman.StopContainer().Call(hnd.ctx, hnd.conn, name, timeout)
+	return hnd.backend.StopContainer(name, timeout)
 }
 
 func (hnd Handle) StartContainer(contID string) (string, error) {
-	return iopodman.StartContainer().Call(hnd.ctx, hnd.conn, contID)
+	return hnd.backend.StartContainer(contID)
 }
 
 func (hnd Handle) WaitContainer(name string, interval int64) (int64, error) {
-	return iopodman.WaitContainer().Call(hnd.ctx, hnd.conn, name, interval)
+	return hnd.backend.WaitContainer(name, interval)
+}
+
+func (hnd Handle) ListImages() ([]iopodman.Image, error) {
+	return hnd.backend.ListImages()
 }
 
-func (hnd Handle) PullImage(ref string, out io.Writer) error {
+// PullImage pulls ref, retrying a few times on failure. progress, if
+// non-nil, is called for every layer status update Podman reports; pass
+// nil to pull silently.
+func (hnd Handle) PullImage(ref string, opts PullImageOpts, progress func(PullEvent)) error {
 	tries := []int{0, 1, 2, 6}
 	for idx, i := range tries {
 		time.Sleep(time.Duration(i) * time.Second)
 
 		log.Printf("attempt #%d to download %s\n", idx, ref)
 
-		// TODO: print _some_ progress while this is going forward
-		_, err := iopodman.PullImage().Call(hnd.ctx, hnd.conn, ref)
+		err := hnd.backend.PullImage(ref, opts, progress)
 		if err != nil {
 			log.Printf("failed to download %s: %v\n", ref, err)
 			continue
@@ -280,4 +322,4 @@ func (hnd Handle) PullImage(ref string, out io.Writer) error {
 		return nil
 	}
 	return fmt.Errorf("failed to download %s %d times, giving up.", ref, len(tries))
-}
\ No newline at end of file
+}
@@ -261,7 +261,7 @@ var _ = Describe("podman", func() {
 			handler, err := podman.NewHandle(ctx, "")
 			Expect(err).To(BeNil())
 
-			err = handler.PullImage(images.DockerRegistryImage)
+			err = handler.PullImage(images.DockerRegistryImage, podman.PullImageOpts{}, nil)
 			Expect(err).To(BeNil())
 
 			images, err := handler.ListImages()
@@ -0,0 +1,35 @@
+package podman
+
+// PullImageOpts carries the registry auth and TLS settings PullImage
+// needs to pull from private registries, such as the ones used in
+// KubeVirt CI.
+type PullImageOpts struct {
+	Username string
+	Password string
+	// Token is used instead of Username/Password for registries that
+	// authenticate with a bearer token.
+	Token string
+	// Registry overrides the registry the reference is resolved
+	// against, mirroring `podman pull --registry`.
+	Registry string
+	// TLSVerify defaults to true; set to false to allow pulling from a
+	// registry with a self-signed or otherwise unverifiable certificate.
+	TLSVerify *bool
+}
+
+// PullEvent reports the progress of a single layer download, or the
+// final digest of the pulled image once the pull completes.
+type PullEvent struct {
+	// Layer is the id of the layer this event is about, empty for the
+	// final "pull complete" event.
+	Layer string
+	// Status is the human readable status Podman reports for this
+	// event, e.g. "Downloading" or "Pull complete".
+	Status string
+	// Current and Total are the bytes downloaded so far and the total
+	// layer size; both are zero when Podman doesn't report them.
+	Current int64
+	Total   int64
+	// Digest is set on the final event once the image has been pulled.
+	Digest string
+}
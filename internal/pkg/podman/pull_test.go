@@ -0,0 +1,55 @@
+package podman
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+func TestRegistryAuthHeaderNoCredentials(t *testing.T) {
+	header, ok := registryAuthHeader(PullImageOpts{})
+	if ok {
+		t.Fatalf("expected no header without credentials, got %q", header)
+	}
+}
+
+func TestRegistryAuthHeaderUsernamePassword(t *testing.T) {
+	header, ok := registryAuthHeader(PullImageOpts{Username: "user", Password: "pass"})
+	if !ok {
+		t.Fatal("expected a header for username/password credentials")
+	}
+
+	decoded, err := base64.URLEncoding.DecodeString(header)
+	if err != nil {
+		t.Fatalf("header is not valid base64: %v", err)
+	}
+	var auth map[string]string
+	if err := json.Unmarshal(decoded, &auth); err != nil {
+		t.Fatalf("header does not decode to JSON: %v", err)
+	}
+	if auth["username"] != "user" || auth["password"] != "pass" {
+		t.Fatalf("unexpected auth payload: %v", auth)
+	}
+}
+
+func TestRegistryAuthHeaderTokenTakesPrecedence(t *testing.T) {
+	header, ok := registryAuthHeader(PullImageOpts{Username: "user", Password: "pass", Token: "tok"})
+	if !ok {
+		t.Fatal("expected a header for token credentials")
+	}
+
+	decoded, err := base64.URLEncoding.DecodeString(header)
+	if err != nil {
+		t.Fatalf("header is not valid base64: %v", err)
+	}
+	var auth map[string]string
+	if err := json.Unmarshal(decoded, &auth); err != nil {
+		t.Fatalf("header does not decode to JSON: %v", err)
+	}
+	if auth["identitytoken"] != "tok" {
+		t.Fatalf("expected identitytoken auth, got %v", auth)
+	}
+	if _, ok := auth["username"]; ok {
+		t.Fatalf("token auth should not also carry username/password: %v", auth)
+	}
+}